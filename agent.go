@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// Agent は --agent で選択される、システムプロンプトと利用可能な
+// ツール名の組を表す。
+type Agent struct {
+	Name   string
+	System string
+	Tools  []string
+}
+
+// loadAgent は設定ファイルから name のエージェント定義を読み込む。
+// name が空文字の場合はツールを一切持たないゼロ値の Agent を返し、
+// 既存の chunk0-1〜chunk0-4 の動作（エージェントモード無し）を維持する。
+func loadAgent(cfg provider.Config, name string) (Agent, error) {
+	if name == "" {
+		return Agent{}, nil
+	}
+	ac, ok := cfg.Agents[name]
+	if !ok {
+		return Agent{}, fmt.Errorf("未定義のエージェントです: %s", name)
+	}
+	return Agent{Name: name, System: ac.System, Tools: ac.Tools}, nil
+}
+
+// builtinTools は agent モードで利用できる組み込みツールの一覧。
+// いずれもカレントディレクトリ配下に限定して実行される。
+var builtinTools = map[string]provider.ToolSpec{
+	"read_file": {
+		Name:        "read_file",
+		Description: "カレントディレクトリ配下にあるファイルを読み込み、その内容を返す",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string", "description": "カレントディレクトリからの相対パス"},
+			},
+			"required": []string{"path"},
+		},
+	},
+	"write_file": {
+		Name:        "write_file",
+		Description: "カレントディレクトリ配下のファイルに内容を書き込む（既存のファイルは上書きされる）",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":    map[string]any{"type": "string", "description": "カレントディレクトリからの相対パス"},
+				"content": map[string]any{"type": "string", "description": "書き込む内容"},
+			},
+			"required": []string{"path", "content"},
+		},
+	},
+	"modify_file": {
+		Name:        "modify_file",
+		Description: "カレントディレクトリ配下のファイル内の文字列を置換する",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path":       map[string]any{"type": "string", "description": "カレントディレクトリからの相対パス"},
+				"old_string": map[string]any{"type": "string", "description": "置換対象の文字列（ファイル内で一意である必要がある）"},
+				"new_string": map[string]any{"type": "string", "description": "置換後の文字列"},
+			},
+			"required": []string{"path", "old_string", "new_string"},
+		},
+	},
+	"list_dir": {
+		Name:        "list_dir",
+		Description: "カレントディレクトリ配下のディレクトリ内容を一覧する",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{"type": "string", "description": "カレントディレクトリからの相対パス（省略時はカレントディレクトリ）"},
+			},
+		},
+	},
+	"run_shell": {
+		Name:        "run_shell",
+		Description: "カレントディレクトリでシェルコマンドを実行し、標準出力・標準エラーを返す",
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"command": map[string]any{"type": "string", "description": "実行するシェルコマンド"},
+			},
+			"required": []string{"command"},
+		},
+	},
+}
+
+// toolSpecsFor は name のリストを builtinTools から引き、見つかった分だけ
+// provider.ToolSpec のスライスとして返す。未知の名前は警告して無視する。
+func toolSpecsFor(names []string) []provider.ToolSpec {
+	specs := make([]provider.ToolSpec, 0, len(names))
+	for _, name := range names {
+		spec, ok := builtinTools[name]
+		if !ok {
+			fmt.Printf("不明なツールです（無視します）: %s\n", name)
+			continue
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// resolveInCWD は rel をカレントディレクトリ基準の絶対パスに解決し、
+// その結果がカレントディレクトリの外を指していないことを確認する。
+// パス中にシンボリックリンクがあるとレキシカルなチェックだけでは
+// すり抜けられてしまうため、実体のパスもカレントディレクトリ配下に
+// あることを確認する（リンク先がまだ存在しない write_file 先でも
+// 動くよう、存在しない末尾部分は実体解決をスキップする）。
+//
+// なお run_shell ツールは任意のシェルコマンドを実行でき、その中身は
+// このチェックの対象外なので、このガードはあくまで read_file /
+// write_file / modify_file / list_dir の誤操作・経路逸脱を防ぐもので
+// あり、万能のサンドボックスではない。
+func resolveInCWD(rel string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	realCWD, err := filepath.EvalSymlinks(cwd)
+	if err != nil {
+		return "", err
+	}
+
+	abs := filepath.Clean(filepath.Join(cwd, rel))
+	if abs != cwd && !strings.HasPrefix(abs, cwd+string(os.PathSeparator)) {
+		return "", fmt.Errorf("カレントディレクトリの外を指すパスです: %s", rel)
+	}
+
+	real, err := resolveExistingSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	if real != realCWD && !strings.HasPrefix(real, realCWD+string(os.PathSeparator)) {
+		return "", fmt.Errorf("シンボリックリンクの参照先がカレントディレクトリの外を指しています: %s", rel)
+	}
+	return abs, nil
+}
+
+// resolveExistingSymlinks は path に含まれるシンボリックリンクを解決する。
+// path 自体やその一部がまだ存在しない場合（write_file で新規作成する
+// ファイルなど）は EvalSymlinks がエラーになるため、実在する一番深い
+// 祖先ディレクトリまで遡って解決し、存在しない末尾部分はそのまま
+// 付け足す。
+func resolveExistingSymlinks(path string) (string, error) {
+	path = filepath.Clean(path)
+	if _, err := os.Lstat(path); err == nil {
+		return filepath.EvalSymlinks(path)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := resolveExistingSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
+
+// runTool は name のツールを argsJSON (モデルが生成した生の JSON 引数)で
+// 実行し、その出力をテキストとして返す。
+func runTool(name, argsJSON string) (string, error) {
+	switch name {
+	case "read_file":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return "", err
+		}
+		path, err := resolveInCWD(args.Path)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+
+	case "write_file":
+		var args struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return "", err
+		}
+		path, err := resolveInCWD(args.Path)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(args.Content), 0644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s に書き込みました", args.Path), nil
+
+	case "modify_file":
+		var args struct {
+			Path      string `json:"path"`
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		}
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return "", err
+		}
+		path, err := resolveInCWD(args.Path)
+		if err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		switch n := strings.Count(string(data), args.OldString); {
+		case n == 0:
+			return "", fmt.Errorf("old_string がファイル内に見つかりません: %s", args.Path)
+		case n > 1:
+			return "", fmt.Errorf("old_string がファイル内に %d 箇所あり、一意に特定できません: %s", n, args.Path)
+		}
+		updated := strings.Replace(string(data), args.OldString, args.NewString, 1)
+		if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s を更新しました", args.Path), nil
+
+	case "list_dir":
+		var args struct {
+			Path string `json:"path"`
+		}
+		_ = json.Unmarshal([]byte(argsJSON), &args)
+		rel := args.Path
+		if rel == "" {
+			rel = "."
+		}
+		path, err := resolveInCWD(rel)
+		if err != nil {
+			return "", err
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", err
+		}
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			if e.IsDir() {
+				names = append(names, e.Name()+"/")
+			} else {
+				names = append(names, e.Name())
+			}
+		}
+		return strings.Join(names, "\n"), nil
+
+	case "run_shell":
+		var args struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return "", err
+		}
+		cmd := exec.Command("sh", "-c", args.Command)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return string(out), err
+		}
+		return string(out), nil
+
+	default:
+		return "", fmt.Errorf("不明なツールです: %s", name)
+	}
+}
+
+// confirmToolCall はツール呼び出しの提案をターミナルに表示し、
+// y(実行)/n(拒否)/e(引数を編集)の確認を求める。編集した場合は
+// 新しい引数で再度確認を求める。戻り値は最終的な引数と、実行してよいか
+// どうかの真偽値。
+func confirmToolCall(scanner *bufio.Scanner, call provider.ToolCall) (args string, approved bool) {
+	args = call.Arguments
+	for {
+		fmt.Println("---------")
+		fmt.Printf("ツール呼び出しの提案: %s(%s)\n", call.Name, args)
+		fmt.Print("実行しますか？ [y/n/e] > ")
+		if !scanner.Scan() {
+			return args, false
+		}
+		switch strings.TrimSpace(scanner.Text()) {
+		case "y":
+			return args, true
+		case "n":
+			return args, false
+		case "e":
+			fmt.Print("新しい引数(JSON)を入力してください > ")
+			if !scanner.Scan() {
+				return args, false
+			}
+			args = scanner.Text()
+		default:
+			fmt.Println("y, n, e のいずれかを入力してください。")
+		}
+	}
+}
+
+// runAgentTurn は tool_calls に対応したターンを実行する。
+// assistant がプレーンテキストの返答を返すまで、提案されたツール呼び出し
+// それぞれについて confirmToolCall で確認し、承認されたものだけ runTool
+// で実行して結果を会話に積み戻す、というループを繰り返す。
+// 呼び出しと結果は都度 Markdown に永続化されるので、中断しても
+// parseChatHistory で呼び出しグラフごと復元できる。
+func runAgentTurn(ctx context.Context, tc provider.ToolCaller, scanner *bufio.Scanner, f *os.File, messages []provider.Message, params provider.Params, tools []provider.ToolSpec) ([]provider.Message, error) {
+	for {
+		result, err := tc.CompleteWithTools(ctx, messages, params, tools)
+		if err != nil {
+			return messages, err
+		}
+
+		if len(result.ToolCalls) == 0 {
+			messages = append(messages, result.Message)
+			writeMarkdown(f, "Assistant", result.Message.Content)
+			return messages, nil
+		}
+
+		messages = append(messages, provider.Message{Role: "assistant", ToolCalls: result.ToolCalls})
+		writeToolCall(f, result.ToolCalls)
+
+		for _, call := range result.ToolCalls {
+			args, approved := confirmToolCall(scanner, call)
+			var output string
+			if !approved {
+				output = "ユーザーが実行を拒否しました"
+			} else if out, err := runTool(call.Name, args); err != nil {
+				output = fmt.Sprintf("error: %s", err)
+			} else {
+				output = out
+			}
+			fmt.Println(output)
+
+			messages = append(messages, provider.Message{Role: "tool", Content: output, ToolCallID: call.ID})
+			writeToolResult(f, call.ID, output)
+		}
+	}
+}
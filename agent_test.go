@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// withCWD は dir をカレントディレクトリにしてテストを実行し、終了後に
+// 元のカレントディレクトリへ戻す。
+func withCWD(t *testing.T, dir string) {
+	t.Helper()
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(orig); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestResolveInCWD(t *testing.T) {
+	dir := t.TempDir()
+	withCWD(t, dir)
+
+	if got, err := resolveInCWD("."); err != nil {
+		t.Fatalf("\".\" 解決時に予期しないエラー: %v", err)
+	} else if got != dir {
+		t.Fatalf("got %q, want %q", got, dir)
+	}
+
+	if got, err := resolveInCWD("sub/file.txt"); err != nil {
+		t.Fatalf("カレントディレクトリ配下のパス解決に予期しないエラー: %v", err)
+	} else if want := filepath.Join(dir, "sub", "file.txt"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	for _, escaping := range []string{"../escape.txt", "../../etc/passwd", "sub/../../escape.txt"} {
+		if _, err := resolveInCWD(escaping); err == nil {
+			t.Errorf("%q はカレントディレクトリの外を指すのにエラーになりませんでした", escaping)
+		}
+	}
+}
+
+// TestResolveInCWDSymlinkEscape は、カレントディレクトリ配下にある
+// シンボリックリンクが外部を指している場合に、レキシカルなチェックを
+// すり抜けられないことを確認する。
+func TestResolveInCWDSymlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("top-secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("シンボリックリンクを作成できない環境のためスキップ: %v", err)
+	}
+	withCWD(t, dir)
+
+	if _, err := resolveInCWD("link/secret.txt"); err == nil {
+		t.Fatal("カレントディレクトリ外を指すシンボリックリンク経由のパスがエラーになりませんでした")
+	}
+
+	// シンボリックリンク先がまだ存在しない場合（write_file で新規作成
+	// する場合に相当）でも、祖先ディレクトリの実体解決は機能する必要が
+	// ある。
+	if got, err := resolveInCWD("sub/new.txt"); err != nil {
+		t.Fatalf("未作成ファイルへのパス解決に予期しないエラー: %v", err)
+	} else if want := filepath.Join(dir, "sub", "new.txt"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// fakeToolCaller は runAgentTurn をテストするための provider.ToolCaller の
+// スタブ。CompleteWithTools が呼ばれるたびに results を順番に返す。
+type fakeToolCaller struct {
+	results []provider.ToolResult
+	calls   int
+}
+
+func (f *fakeToolCaller) CompleteWithTools(ctx context.Context, messages []provider.Message, params provider.Params, tools []provider.ToolSpec) (provider.ToolResult, error) {
+	if f.calls >= len(f.results) {
+		return provider.ToolResult{}, errors.New("fakeToolCaller: no more scripted results")
+	}
+	result := f.results[f.calls]
+	f.calls++
+	return result, nil
+}
+
+func newScanner(input string) *bufio.Scanner {
+	return bufio.NewScanner(strings.NewReader(input))
+}
+
+func TestRunAgentTurnApprovedToolCall(t *testing.T) {
+	dir := t.TempDir()
+	withCWD(t, dir)
+	if err := os.WriteFile("greeting.txt", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &fakeToolCaller{
+		results: []provider.ToolResult{
+			{ToolCalls: []provider.ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path":"greeting.txt"}`}}},
+			{Message: provider.Message{Role: "assistant", Content: "done"}},
+		},
+	}
+
+	logPath := filepath.Join(dir, "chat.md")
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	messages, err := runAgentTurn(context.Background(), tc, newScanner("y\n"), f, nil, provider.Params{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3 (assistant tool_calls, tool result, final assistant): %+v", len(messages), messages)
+	}
+	if messages[0].Role != "assistant" || len(messages[0].ToolCalls) != 1 {
+		t.Fatalf("messages[0] should be the assistant's tool_calls turn, got %+v", messages[0])
+	}
+	if messages[1].Role != "tool" || messages[1].ToolCallID != "call_1" || messages[1].Content != "hello" {
+		t.Fatalf("messages[1] should be the承認済み tool result, got %+v", messages[1])
+	}
+	if messages[2].Role != "assistant" || messages[2].Content != "done" {
+		t.Fatalf("messages[2] should be the final assistant reply, got %+v", messages[2])
+	}
+}
+
+func TestRunAgentTurnRejectedToolCall(t *testing.T) {
+	dir := t.TempDir()
+	withCWD(t, dir)
+	if err := os.WriteFile("secret.txt", []byte("do-not-read"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &fakeToolCaller{
+		results: []provider.ToolResult{
+			{ToolCalls: []provider.ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path":"secret.txt"}`}}},
+			{Message: provider.Message{Role: "assistant", Content: "ok, skipped"}},
+		},
+	}
+
+	logPath := filepath.Join(dir, "chat.md")
+	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	messages, err := runAgentTurn(context.Background(), tc, newScanner("n\n"), f, nil, provider.Params{}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages, want 3: %+v", len(messages), messages)
+	}
+	if messages[1].Role != "tool" || !strings.Contains(messages[1].Content, "拒否") {
+		t.Fatalf("rejected tool call should record a refusal, got %+v", messages[1])
+	}
+	if strings.Contains(messages[1].Content, "do-not-read") {
+		t.Fatalf("rejected tool call must not execute and leak file contents: %+v", messages[1])
+	}
+}
+
+func TestConfirmToolCallEdit(t *testing.T) {
+	call := provider.ToolCall{ID: "call_1", Name: "write_file", Arguments: `{"path":"a.txt","content":"old"}`}
+	args, approved := confirmToolCall(newScanner("e\n{\"path\":\"a.txt\",\"content\":\"new\"}\ny\n"), call)
+	if !approved {
+		t.Fatal("expected approval after edit")
+	}
+	if args != `{"path":"a.txt","content":"new"}` {
+		t.Fatalf("got args %q after edit", args)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// branchCommentPrefix は forkAtMessage が書き込む
+// "<!-- chatmd: branch parent=<file> at=<n> -->" 形式のヘッダーコメントの
+// 接頭辞。selectChatFile がこれを読み取って分岐元との親子関係を表示する。
+const branchCommentPrefix = "<!-- chatmd: branch parent="
+
+// parseBranchComment は1行が branch ヘッダーコメントかどうかを判定し、
+// そうであれば分岐元ファイル名と分岐位置(n)を返す。
+func parseBranchComment(line string) (parent string, at int, ok bool) {
+	if !strings.HasPrefix(line, branchCommentPrefix) || !strings.HasSuffix(line, commentSuffix) {
+		return "", 0, false
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(line, branchCommentPrefix), commentSuffix)
+	parentPart, atPart, ok := strings.Cut(body, " at=")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(atPart)
+	if err != nil {
+		return "", 0, false
+	}
+	return parentPart, n, true
+}
+
+// truncateMessageLines は path を parseChatHistory と同じ規則（"## " で
+// 始まる行がメッセージの区切り）で走査し、先頭から n 個目のメッセージが
+// 始まる直前までの生テキストを返す。n が総メッセージ数以上であれば
+// ファイル全体を返す。
+func truncateMessageLines(path string, n int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var kept []string
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "## ") {
+			if count == n {
+				break
+			}
+			count++
+		}
+		kept = append(kept, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if len(kept) == 0 {
+		return "", nil
+	}
+	return strings.Join(kept, "\n") + "\n", nil
+}
+
+// firstHeadingIsSystem は path 内で最初に現れる "## " 見出しが
+// "## System" かどうかを返す。
+func firstHeadingIsSystem(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "## ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "## ")) == "System"
+		}
+	}
+	return false
+}
+
+// forkAtMessage は current の内容を n 番目のメッセージの手前までに切り詰め、
+// chats/<name>__branch_<timestamp>.md として書き出す。先頭には
+// "<!-- chatmd: branch parent=... at=... -->" ヘッダーを付与し、
+// selectChatFile が分岐の親子関係をたどれるようにする。
+//
+// n は messages（/context が表示する番号）の添字なので、
+// "<!-- chatmd: system=... -->" コメントのみから復元され "## System"
+// 見出しを持たないシステムメッセージが先頭にある場合、ファイル中の
+// "## " 見出し数とは1つずれる。そのずれを補正してから
+// truncateMessageLines に渡す。
+// 戻り値は追記モードで開いた新しいファイルのハンドル。
+func forkAtMessage(current *os.File, sess *Session, n int) (*os.File, error) {
+	fileN := n
+	if sess.System != "" && !firstHeadingIsSystem(current.Name()) {
+		fileN--
+		if fileN < 0 {
+			fileN = 0
+		}
+	}
+
+	truncated, err := truncateMessageLines(current.Name(), fileN)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := filepath.Base(current.Name())
+	newName := fmt.Sprintf("%s__branch_%s.md", strings.TrimSuffix(parent, ".md"), nowString())
+	newPath := filepath.Join(filepath.Dir(current.Name()), newName)
+
+	header := fmt.Sprintf("<!-- chatmd: branch parent=%s at=%d -->\n\n", parent, n)
+	if err := os.WriteFile(newPath, []byte(header+truncated), 0644); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(newPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// editInEditor は initial の内容を一時ファイルに書き出して $EDITOR
+// （未設定なら vi）で開き、保存された内容を返す。
+func editInEditor(initial string) (string, error) {
+	tmp, err := os.CreateTemp("", "chatmd-edit-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(initial); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command(editor, tmp.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("エディタの起動に失敗しました: %w", err)
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseBranchComment(t *testing.T) {
+	parent, at, ok := parseBranchComment("<!-- chatmd: branch parent=chat.md at=3 -->")
+	if !ok || parent != "chat.md" || at != 3 {
+		t.Fatalf("got parent=%q at=%d ok=%v", parent, at, ok)
+	}
+
+	if _, _, ok := parseBranchComment("## User"); ok {
+		t.Fatal("non-branch-comment line should not parse as one")
+	}
+}
+
+func TestTruncateMessageLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.md")
+	content := "## User\n\nhello\n\n## Assistant\n\nhi there\n\n## User\n\nbye\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := truncateMessageLines(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## User\n\nhello\n\n## Assistant\n\nhi there\n\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// n が総メッセージ数以上の場合はファイル全体が返る。
+	if got, err := truncateMessageLines(path, 100); err != nil {
+		t.Fatal(err)
+	} else if got != content {
+		t.Fatalf("got %q, want the whole file", got)
+	}
+}
+
+func TestFirstHeadingIsSystem(t *testing.T) {
+	dir := t.TempDir()
+
+	withSystem := filepath.Join(dir, "with_system.md")
+	if err := os.WriteFile(withSystem, []byte("## System\n\nbe helpful\n\n## User\n\nhi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !firstHeadingIsSystem(withSystem) {
+		t.Fatal("expected true when the first heading is \"## System\"")
+	}
+
+	withoutSystem := filepath.Join(dir, "without_system.md")
+	if err := os.WriteFile(withoutSystem, []byte("## User\n\nhi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if firstHeadingIsSystem(withoutSystem) {
+		t.Fatal("expected false when the first heading is not \"## System\"")
+	}
+}
+
+// TestForkAtMessageSystemOffset は、System メッセージが "<!-- chatmd:
+// system=... -->" コメントのみから復元され、ファイル中に "## System"
+// 見出しを持たない場合でも、forkAtMessage が n をファイルの "## " 見出し数に
+// 正しく合わせてから切り詰めることを確認する。
+func TestForkAtMessageSystemOffset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "chat.md")
+	// system=... コメントのみがあり、"## System" 見出しを持たない
+	// ファイル。messages スライスでは index 0 がこの合成システム
+	// メッセージ、index 1, 2 がそれぞれ以下の "## User", "## Assistant"
+	// に対応する。
+	content := "<!-- chatmd: system=be helpful -->\n\n## User\n\nhello\n\n## Assistant\n\nhi there\n\n## User\n\nbye\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sess := &Session{System: "be helpful"}
+
+	// n=2 は messages スライス上で2番目の "## " 見出し（"## Assistant"）の
+	// 直前を指すので、切り詰め後のファイルには "## User" のメッセージ
+	// だけが残るはず。
+	branch, err := forkAtMessage(f, sess, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer branch.Close()
+
+	data, err := os.ReadFile(branch.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "## User") || strings.Contains(string(data), "## Assistant") {
+		t.Fatalf("branch file has unexpected content: %q", data)
+	}
+	if !strings.HasPrefix(string(data), branchCommentPrefix) {
+		t.Fatalf("branch file must start with the branch header comment, got %q", data)
+	}
+}
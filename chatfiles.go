@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ------------------------------
+// ここから下は選択・ファイル名生成用のユーティリティ
+// ------------------------------
+
+// selectChatFile は、chats/ フォルダ内のファイルを一覧表示し、
+// その中から1つ選ばせるか「新規チャット」を選ばせる。
+// /branch や /edit で分岐したファイルは、分岐元の下にツリー状に
+// インデントして表示される。
+//
+// 選ばれたファイル名（"xxx.md"）を返す。新規なら空文字("")を返す。
+func selectChatFile() (string, error) {
+	files, err := listMarkdownFiles("chats")
+	if err != nil {
+		return "", err
+	}
+	ordered := orderChatFilesAsTree("chats", files)
+
+	// 表示用に "新規チャット" を末尾に追加
+	fmt.Println("▼チャットを選択してください:")
+	fmt.Printf("[%d] 新規チャット\n", 0)
+	for i, entry := range ordered {
+		fmt.Printf("[%d] %s%s\n", i+1, strings.Repeat("  ", entry.depth), entry.name)
+	}
+
+	// 入力受付
+	var s string
+	for {
+		fmt.Print("選択番号を入力してください > ")
+		fmt.Scanln(&s)
+		idx, err := strconv.Atoi(s)
+		if err != nil {
+			fmt.Println("数値を入力してください。")
+			continue
+		}
+		if idx < 0 || idx > len(ordered) {
+			fmt.Println("選択肢の番号を入力してください。")
+			continue
+		}
+
+		// 選択肢の最後が「新規チャット」
+		if idx == 0 {
+			// 新規チャット
+			return "", nil
+		}
+
+		// 既存ファイルが選ばれた
+		return ordered[idx-1].name, nil
+	}
+}
+
+// chatFileEntry は selectChatFile の表示1行分: ファイル名と、
+// ツリー表示のためのインデント深さ。
+type chatFileEntry struct {
+	name  string
+	depth int
+}
+
+// orderChatFilesAsTree は files を、分岐元ファイルの直下にその
+// 分岐先を並べたツリー順に並び替える。
+func orderChatFilesAsTree(dir string, files []string) []chatFileEntry {
+	children := map[string][]string{}
+	isBranch := map[string]bool{}
+	for _, name := range files {
+		if parent, _, ok := detectBranchParent(dir, name); ok {
+			children[parent] = append(children[parent], name)
+			isBranch[name] = true
+		}
+	}
+
+	var ordered []chatFileEntry
+	var visit func(name string, depth int)
+	visit = func(name string, depth int) {
+		ordered = append(ordered, chatFileEntry{name: name, depth: depth})
+		for _, child := range children[name] {
+			visit(child, depth+1)
+		}
+	}
+	for _, name := range files {
+		if !isBranch[name] {
+			visit(name, 0)
+		}
+	}
+	// 分岐元が一覧に存在しない孤立ブランチ（分岐元を削除済みなど）も
+	// 末尾にそのまま表示する
+	for _, name := range files {
+		seen := false
+		for _, e := range ordered {
+			if e.name == name {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			visit(name, 0)
+		}
+	}
+	return ordered
+}
+
+// detectBranchParent は path のファイル先頭付近を走査し、forkAtMessage が
+// 書き込んだ branch ヘッダーコメントから分岐元ファイル名を読み取る。
+func detectBranchParent(dir, name string) (parent string, at int, ok bool) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return "", 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < 5 && scanner.Scan(); i++ {
+		if parent, at, ok := parseBranchComment(scanner.Text()); ok {
+			return parent, at, ok
+		}
+	}
+	return "", 0, false
+}
+
+// listMarkdownFiles は指定ディレクトリ配下の .md ファイルを一覧として返す
+func listMarkdownFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if filepath.Ext(e.Name()) == ".md" {
+			files = append(files, e.Name())
+		}
+	}
+	return files, nil
+}
+
+// createNewChatFileName は、新規チャット用のユニークなファイル名を生成する関数(例: chat_20241225_123456.md)
+func createNewChatFileName() string {
+	// 例: 日付や時刻、UUIDなどを入れる
+	// ここでは簡単に YYYYMMDD_HHMMSS 形式を例示
+	// 実際には "github.com/google/uuid" など使ってUUIDを生成してもよい
+	// あるいはユーザーにファイル名を入力させてもよい
+	return fmt.Sprintf("chat_%s.md", nowString())
+}
+
+// nowString は"YYYYMMDD_HHMMSS" 形式の文字列を返す
+func nowString() string {
+	// 現在時刻を"YYYYMMDD_HHMMSS" 形式の文字列に変換
+	return time.Now().Format("20060102_150405")
+}
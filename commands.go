@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// commandResult はスラッシュコマンド実行後に REPL ループが取るべき
+// アクションを表す。
+type commandResult struct {
+	// exit が true の場合、REPL を終了する。
+	exit bool
+	// newFile が非nilの場合、/branch や /edit で分岐した新しいファイルに
+	// 以降の追記先を切り替える。
+	newFile *os.File
+	// generate が true の場合、/edit で追加した User メッセージに対する
+	// 応答生成を直ちに行う。
+	generate bool
+}
+
+// isCommand は入力がスラッシュコマンドかどうかを判定する。
+func isCommand(input string) bool {
+	return strings.HasPrefix(input, "/")
+}
+
+// handleCommand はスラッシュコマンドを解釈し、Session と messages を
+// 更新する。パラメータを変更するコマンドは persistParam を通じて
+// Markdown に HTML コメントとして記録され、parseChatHistory が
+// 再開時にそれを読み取って Session を復元する。
+func handleCommand(sess *Session, f *os.File, messages []provider.Message, input string) ([]provider.Message, commandResult) {
+	fields := strings.Fields(input)
+	name := fields[0]
+	arg := strings.TrimSpace(strings.TrimPrefix(input, name))
+
+	switch name {
+	case "/model":
+		if arg == "" {
+			fmt.Println("使い方: /model <name>")
+			return messages, commandResult{}
+		}
+		sess.Model = arg
+		persistParam(f, "model", arg)
+		fmt.Printf("モデルを %s に変更しました\n", arg)
+
+	case "/temp":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			fmt.Println("使い方: /temp <float>")
+			return messages, commandResult{}
+		}
+		sess.Temperature = &v
+		persistParam(f, "temperature", arg)
+		fmt.Printf("temperature を %v に変更しました\n", v)
+
+	case "/topp":
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			fmt.Println("使い方: /topp <float>")
+			return messages, commandResult{}
+		}
+		sess.TopP = &v
+		persistParam(f, "top_p", arg)
+		fmt.Printf("top_p を %v に変更しました\n", v)
+
+	case "/max_tokens":
+		v, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			fmt.Println("使い方: /max_tokens <int>")
+			return messages, commandResult{}
+		}
+		sess.MaxTokens = &v
+		persistParam(f, "max_tokens", arg)
+		fmt.Printf("max_tokens を %d に変更しました\n", v)
+
+	case "/system":
+		if arg == "" {
+			fmt.Println("使い方: /system <text>")
+			return messages, commandResult{}
+		}
+		sess.System = arg
+		messages = applySystemMessage(messages, arg)
+		persistParam(f, "system", arg)
+		fmt.Println("システムメッセージを更新しました")
+
+	case "/clear":
+		messages = messages[:0]
+		fmt.Println("会話履歴をクリアしました（ファイルは削除されません）")
+
+	case "/context":
+		summarizeContext(messages)
+
+	case "/save":
+		if arg == "" {
+			fmt.Println("使い方: /save <name>")
+			return messages, commandResult{}
+		}
+		if err := forkChat(f.Name(), arg); err != nil {
+			fmt.Println("Error:", err)
+		}
+
+	case "/branch":
+		n, ok := parseMessageIndex(arg, len(messages))
+		if !ok {
+			fmt.Println("使い方: /branch <n>（n は /context に表示されるメッセージ番号）")
+			return messages, commandResult{}
+		}
+		newFile, err := forkAtMessage(f, sess, n)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return messages, commandResult{}
+		}
+		fmt.Printf("'%s' に %d 番目のメッセージまでを分岐しました\n", filepath.Base(newFile.Name()), n)
+		return messages[:n], commandResult{newFile: newFile}
+
+	case "/edit":
+		n, ok := parseMessageIndex(arg, len(messages))
+		if !ok || n >= len(messages) || messages[n].Role != "user" {
+			fmt.Println("使い方: /edit <n>（n は User メッセージの番号）")
+			return messages, commandResult{}
+		}
+		edited, err := editInEditor(messages[n].Content)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return messages, commandResult{}
+		}
+		newFile, err := forkAtMessage(f, sess, n)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return messages, commandResult{}
+		}
+		writeMarkdown(newFile, "User", edited)
+		fmt.Printf("'%s' に分岐し、%d 番目のメッセージを編集しました\n", filepath.Base(newFile.Name()), n)
+		return append(messages[:n:n], userMessageWithImages(edited)), commandResult{newFile: newFile, generate: true}
+
+	case "/help":
+		printHelp()
+
+	default:
+		fmt.Printf("不明なコマンドです: %s （/help で一覧を表示）\n", name)
+	}
+
+	return messages, commandResult{}
+}
+
+// printHelp は利用可能なスラッシュコマンドの一覧を表示する。
+func printHelp() {
+	fmt.Println("利用可能なコマンド:")
+	fmt.Println("  /model <name>       使用するモデルを切り替える")
+	fmt.Println("  /temp <float>       temperature を設定する")
+	fmt.Println("  /topp <float>       top_p を設定する")
+	fmt.Println("  /max_tokens <int>   max_tokens を設定する")
+	fmt.Println("  /system <text>      システムメッセージを設定する")
+	fmt.Println("  /clear              会話履歴をクリアする（ファイルは保持）")
+	fmt.Println("  /context            会話履歴のトークン数概算を表示する")
+	fmt.Println("  /save <name>        現在の会話を新しいファイルに分岐保存する")
+	fmt.Println("  /branch <n>         n 番目のメッセージ以降を切り離して分岐する")
+	fmt.Println("  /edit <n>           n 番目の User メッセージを $EDITOR で編集し、分岐して再送信する")
+	fmt.Println("  /help               このヘルプを表示する")
+}
+
+// parseMessageIndex は /branch, /edit の引数を 0 以上 max 以下の
+// メッセージ番号として解釈する。
+func parseMessageIndex(arg string, max int) (int, bool) {
+	if arg == "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 || n > max {
+		return 0, false
+	}
+	return n, true
+}
+
+// persistParam はパラメータの変更を Markdown に HTML コメントとして
+// 書き込む。例: <!-- chatmd: model=gpt-4o -->
+func persistParam(f *os.File, key, value string) {
+	fmt.Fprintf(f, "<!-- chatmd: %s=%s -->\n\n", key, value)
+}
+
+// forkChat は現在のチャットファイルの内容を chats/<name>.md にコピーする。
+func forkChat(currentPath, name string) error {
+	data, err := os.ReadFile(currentPath)
+	if err != nil {
+		return err
+	}
+	destPath := filepath.Join(filepath.Dir(currentPath), name+".md")
+	if err := os.WriteFile(destPath, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("'%s' に分岐保存しました\n", filepath.Base(destPath))
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// imageRefPattern は Markdown の画像記法 ![alt](path) を検出する。
+var imageRefPattern = regexp.MustCompile(`!\[[^\]]*\]\(([^)]+)\)`)
+
+// extractImageRefs はテキスト中に含まれる画像参照(ローカルパスまたはURL)を
+// 出現順にすべて返す。
+func extractImageRefs(text string) []string {
+	matches := imageRefPattern.FindAllStringSubmatch(text, -1)
+	refs := make([]string, 0, len(matches))
+	for _, m := range matches {
+		refs = append(refs, m[1])
+	}
+	return refs
+}
+
+// hasImageRefs はテキストに画像参照が含まれているかどうかを返す。
+func hasImageRefs(text string) bool {
+	return imageRefPattern.MatchString(text)
+}
+
+// isRemoteImageRef は ref が http(s) の URL かどうかを判定する。
+func isRemoteImageRef(ref string) bool {
+	u, err := url.Parse(ref)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// imageURLFor はローカルパスを base64 の data URL に変換する。
+// リモートURLの場合はそのまま返す。
+func imageURLFor(ref string) (string, error) {
+	if isRemoteImageRef(ref) {
+		return ref, nil
+	}
+
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("画像の読み込みに失敗しました (%s): %w", ref, err)
+	}
+	mimeType := mime.TypeByExtension(filepath.Ext(ref))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, encoded), nil
+}
+
+// userMessageWithImages は text 中の ![alt](path) 形式の画像参照を検出し、
+// それぞれを data URL (またはリモートURLそのまま) に変換した provider.Message
+// を組み立てる。画像参照が一つもない場合は Images が空のメッセージになる。
+func userMessageWithImages(text string) provider.Message {
+	msg := provider.Message{Role: "user", Content: text}
+	for _, ref := range extractImageRefs(text) {
+		imageURL, err := imageURLFor(ref)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		msg.Images = append(msg.Images, imageURL)
+	}
+	return msg
+}
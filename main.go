@@ -3,20 +3,54 @@ package main
 import (
 	"bufio"
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-	"time"
 
-	"github.com/openai/openai-go"
+	"github.com/tochiji/chatmd/pkg/provider"
 )
 
+// defaultModelFor はプロバイダごとの既定モデルを返す。
+// 設定ファイルにも履歴にもモデル指定がない場合に使われる。
+func defaultModelFor(providerName string) string {
+	switch providerName {
+	case "anthropic":
+		return "claude-3-5-sonnet-20241022"
+	case "google":
+		return "gemini-1.5-pro"
+	case "ollama":
+		return "llama3"
+	default:
+		return "o1"
+	}
+}
+
 func main() {
-	// chats フォルダを作成(存在しない場合は作成する)
-	err := os.MkdirAll("chats", 0755)
+	providerFlag := flag.String("provider", "", "使用するプロバイダ (openai, anthropic, google, ollama)")
+	agentFlag := flag.String("agent", "", "使用するエージェント名（設定ファイルの agents に定義）")
+	flag.Parse()
+
+	cfgPath, err := provider.ConfigPath()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	cfg, err := provider.LoadConfig(cfgPath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	agent, err := loadAgent(cfg, *agentFlag)
 	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// chats フォルダを作成(存在しない場合は作成する)
+	if err := os.MkdirAll("chats", 0755); err != nil {
 		fmt.Println("Error creating chats directory:", err)
 		return
 	}
@@ -33,7 +67,8 @@ func main() {
 	// -----------------------------
 	// 2) 選択されたファイルをパース or 新規ファイルを作成
 	// -----------------------------
-	messages := []openai.ChatCompletionMessageParamUnion{}
+	messages := []provider.Message{}
+	var existingSess *Session
 	var f *os.File
 
 	if chatFile == "" {
@@ -53,12 +88,12 @@ func main() {
 		filePath := filepath.Join("chats", chatFile)
 
 		// まず読み込み用に開いて parse
-		existingMessages, err := parseChatHistory(filePath)
-		if err != nil {
-			fmt.Println("Error parsing chat history:", err)
+		var parseErr error
+		messages, existingSess, parseErr = parseChatHistory(filePath)
+		if parseErr != nil {
+			fmt.Println("Error parsing chat history:", parseErr)
 			return
 		}
-		messages = append(messages, existingMessages...)
 
 		// 読み込み用ファイルを閉じた後、追記モードで開き直す
 		f, err = os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
@@ -71,14 +106,76 @@ func main() {
 	defer f.Close()
 
 	// -----------------------------
-	// 3) 通常どおり ChatGPT CLI を開始
+	// 3) 使用するプロバイダを決定する
+	//    優先順位: --provider フラグ > 会話履歴に記録されたプロバイダ
+	//    （再開時） > 設定ファイルの default_provider > "openai"
+	// -----------------------------
+	providerName := *providerFlag
+	if providerName == "" && existingSess != nil {
+		providerName = existingSess.Provider
+	}
+	if providerName == "" {
+		providerName = cfg.DefaultProvider
+	}
+	if providerName == "" {
+		providerName = "openai"
+	}
+
+	chatProvider, err := provider.New(providerName, cfg)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	sess := existingSess
+	if sess == nil {
+		sess = newSession(providerName, "")
+	}
+	if sess.Provider != "" && sess.Provider != providerName {
+		// プロバイダが切り替わった場合、以前のプロバイダのモデル名を
+		// 引き継ぐと新しいプロバイダでは存在しないモデルになりうるため、
+		// 新しいプロバイダのデフォルトを選び直す。
+		sess.Model = ""
+	}
+	sess.Provider = providerName
+	if sess.Model == "" {
+		sess.Model = provider.DefaultModel(providerName, cfg)
+	}
+	if sess.Model == "" {
+		sess.Model = defaultModelFor(providerName)
+	}
+
+	// -----------------------------
+	// 4) エージェントモードの準備
+	//    --agent が指定されていれば、その system プロンプトと
+	//    ツール一式を有効にする。ツール呼び出しに対応していない
+	//    プロバイダが選ばれている場合は通常モードにフォールバックする。
+	// -----------------------------
+	if agent.System != "" && sess.System == "" {
+		sess.System = agent.System
+		messages = applySystemMessage(messages, agent.System)
+		persistParam(f, "system", agent.System)
+	}
+
+	toolSpecs := toolSpecsFor(agent.Tools)
+	var toolCaller provider.ToolCaller
+	if len(toolSpecs) > 0 {
+		if tc, ok := chatProvider.(provider.ToolCaller); ok {
+			toolCaller = tc
+		} else {
+			fmt.Printf("プロバイダ '%s' はツール呼び出しに対応していないため、通常モードで応答します\n", sess.Provider)
+		}
+	}
+
+	// -----------------------------
+	// 5) 通常どおり ChatGPT CLI を開始
 	// -----------------------------
-	client := openai.NewClient()
 	ctx := context.Background()
 
 	fmt.Println("-----------------------------------")
-	fmt.Println("ChatGPT CLI を開始します。")
+	fmt.Printf("ChatGPT CLI を開始します（プロバイダ: %s, モデル: %s）。\n", sess.Provider, sess.Model)
 	fmt.Println("会話を送信するには、半角スペースのみの行を入力して改行してください。")
+	fmt.Println("「/」から始まる行はコマンドとして扱われます（/help で一覧表示）。")
 	fmt.Println("終了するには \"exit\" と入力し、さらに空行を入力してください。")
 	fmt.Println("-----------------------------------")
 
@@ -94,29 +191,76 @@ func main() {
 			break
 		}
 
+		if isCommand(userInput) {
+			var res commandResult
+			messages, res = handleCommand(sess, f, messages, userInput)
+			if res.exit {
+				break
+			}
+			if res.newFile != nil {
+				// /branch, /edit で分岐した場合、以降はその新しいファイルに追記する
+				f.Close()
+				f = res.newFile
+			}
+			if res.generate {
+				messages = generateTurn(ctx, chatProvider, toolCaller, scanner, f, messages, sess, toolSpecs)
+			}
+			continue
+		}
+
+		// 画像参照 (![alt](path)) が含まれる場合、ビジョン非対応モデルから
+		// 自動的に gpt-4o へ切り替える
+		if hasImageRefs(userInput) && sess.Provider == "openai" && sess.Model == "o1" {
+			sess.Model = "gpt-4o"
+			persistParam(f, "model", sess.Model)
+			fmt.Printf("画像が検出されたため、モデルを %s に切り替えました\n", sess.Model)
+		}
+
 		// ユーザメッセージを履歴に追加
-		messages = append(messages, openai.UserMessage(userInput))
+		messages = append(messages, userMessageWithImages(userInput))
 
-		// Markdown に追記
+		// Markdown に追記（元の Markdown 表記(![alt](path))をそのまま保存する）
 		writeMarkdown(f, "User", userInput)
 
-		completion, err := client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-			Messages: openai.F(messages),
-			Model:    openai.F(openai.ChatModelO1),
-		})
+		messages = generateTurn(ctx, chatProvider, toolCaller, scanner, f, messages, sess, toolSpecs)
+	}
+}
+
+// generateTurn はユーザメッセージ追加後の1ターン分の応答生成を行う。
+// toolCaller が有効な場合はエージェントモード（tool_calls の確認・実行
+// ループ）、そうでなければ通常のストリーミング応答を使う。どちらの経路
+// でも、生成結果と使用したプロバイダ・モデルを Markdown に永続化する。
+func generateTurn(ctx context.Context, chatProvider provider.ChatProvider, toolCaller provider.ToolCaller, scanner *bufio.Scanner, f *os.File, messages []provider.Message, sess *Session, toolSpecs []provider.ToolSpec) []provider.Message {
+	if toolCaller != nil {
+		updated, err := runAgentTurn(ctx, toolCaller, scanner, f, messages, sess.params(), toolSpecs)
 		if err != nil {
 			fmt.Println("Error:", err)
-			continue
+			return updated
 		}
+		persistParam(f, "provider", sess.Provider)
+		persistParam(f, "model", sess.Model)
+		return updated
+	}
 
-		// AIからの応答
-		assistantMessage := completion.Choices[0].Message.Content
-		fmt.Println(assistantMessage)
-		messages = append(messages, openai.AssistantMessage(assistantMessage))
+	assistantMessage, cancelled, err := runCompletion(ctx, chatProvider, messages, sess.params())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return messages
+	}
+	if cancelled {
+		fmt.Println("[Ctrl-C: 生成を中断しました]")
+	}
 
-		// Markdown に追記
+	// 中断時も含め、受信できた分はそのまま履歴とファイルに反映する
+	if assistantMessage != "" {
+		messages = append(messages, provider.Message{Role: "assistant", Content: assistantMessage})
 		writeMarkdown(f, "Assistant", assistantMessage)
+		// どのプロバイダ・モデルが生成したかをターンごとに記録し、
+		// 別プロバイダでの再開時にも履歴を追えるようにする
+		persistParam(f, "provider", sess.Provider)
+		persistParam(f, "model", sess.Model)
 	}
+	return messages
 }
 
 // readMultilineInput は、複数行を読み取り、空行（半角スペースのみの行）が入力されたらまとめて返す関数
@@ -140,161 +284,3 @@ func readMultilineInput(scanner *bufio.Scanner) string {
 	fmt.Println("---------")
 	return strings.Join(lines, "\n")
 }
-
-// writeMarkdown は、役割(role)とメッセージ(message)を Markdown 形式でファイルに追記する関数
-func writeMarkdown(f *os.File, role, message string) {
-	// 役割ごとに、見出しを付ける。
-	// Userは「## User」、Assistantは「## Assistant」
-	f.WriteString(fmt.Sprintf("## %s\n\n%s\n\n", role, message))
-}
-
-// -----------------------------
-// Markdownをパースして messages に変換する関数
-// -----------------------------
-func parseChatHistory(filename string) ([]openai.ChatCompletionMessageParamUnion, error) {
-	// ファイルを開く
-	f, err := os.Open(filename)
-	if err != nil {
-		// ファイルが存在しないなど
-		if os.IsNotExist(err) {
-			// 無視して空スライスを返す
-			return nil, nil
-		}
-		return nil, err
-	}
-	defer f.Close()
-
-	var messages []openai.ChatCompletionMessageParamUnion
-	var currentRole string
-	var currentLines []string
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// 「## 」で始まる行は"役割"とみなす
-		if strings.HasPrefix(line, "## ") {
-			// もし前の役割とメッセージがたまっていたら messages に追加
-			if currentRole != "" && len(currentLines) > 0 {
-				msg := strings.Join(currentLines, "\n")
-				messages = append(messages, convertToOpenAIPayload(currentRole, msg))
-			}
-			// 新しい役割をセット
-			currentRole = strings.TrimSpace(strings.TrimPrefix(line, "## "))
-			// メッセージバッファを初期化
-			currentLines = []string{}
-		} else {
-			// 役割以外の行はメッセージの本文としてためる
-			// （空行でも改行扱いでつなげたいならここで処理）
-			currentLines = append(currentLines, line)
-		}
-	}
-	// ループ終了後に残っていたら最後に追加
-	if currentRole != "" && len(currentLines) > 0 {
-		msg := strings.Join(currentLines, "\n")
-		messages = append(messages, convertToOpenAIPayload(currentRole, msg))
-	}
-
-	// scanner のエラーチェック
-	if err := scanner.Err(); err != nil {
-		return messages, err
-	}
-
-	return messages, nil
-}
-
-// 役割文字列を openai.ChatCompletionMessageParamUnion に変換する小関数
-func convertToOpenAIPayload(role, content string) openai.ChatCompletionMessageParamUnion {
-	switch role {
-	case "Assistant":
-		return openai.AssistantMessage(content)
-	case "User":
-		return openai.UserMessage(content)
-	default:
-		// デフォルトは User 扱いにする
-		return openai.UserMessage(content)
-	}
-}
-
-// ------------------------------
-// ここから下は選択・ファイル名生成用のユーティリティ
-// ------------------------------
-
-// selectChatFile は、chats/ フォルダ内のファイルを一覧表示し、
-// その中から1つ選ばせるか「新規チャット」を選ばせる。
-//
-// 選ばれたファイル名（"xxx.md"）を返す。新規なら空文字("")を返す。
-func selectChatFile() (string, error) {
-	files, err := listMarkdownFiles("chats")
-	if err != nil {
-		return "", err
-	}
-
-	// 表示用に "新規チャット" を末尾に追加
-	// files には Markdownファイル名の一覧が格納されている
-	fmt.Println("▼チャットを選択してください:")
-	fmt.Printf("[%d] 新規チャット\n", 0)
-	for i, f := range files {
-		fmt.Printf("[%d] %s\n", i+1, f)
-	}
-
-	// 入力受付
-	var s string
-	for {
-		fmt.Print("選択番号を入力してください > ")
-		fmt.Scanln(&s)
-		idx, err := strconv.Atoi(s)
-		if err != nil {
-			fmt.Println("数値を入力してください。")
-			continue
-		}
-		if idx < 0 || idx > len(files) {
-			fmt.Println("選択肢の番号を入力してください。")
-			continue
-		}
-
-		// 選択肢の最後が「新規チャット」
-		if idx == 0 {
-			// 新規チャット
-			return "", nil
-		}
-
-		// 既存ファイルが選ばれた
-		selectedFile := files[idx-1]
-		return selectedFile, nil
-	}
-}
-
-// listMarkdownFiles は指定ディレクトリ配下の .md ファイルを一覧として返す
-func listMarkdownFiles(dir string) ([]string, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, err
-	}
-
-	var files []string
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		if filepath.Ext(e.Name()) == ".md" {
-			files = append(files, e.Name())
-		}
-	}
-	return files, nil
-}
-
-// createNewChatFileName は、新規チャット用のユニークなファイル名を生成する関数(例: chat_20241225_123456.md)
-func createNewChatFileName() string {
-	// 例: 日付や時刻、UUIDなどを入れる
-	// ここでは簡単に YYYYMMDD_HHMMSS 形式を例示
-	// 実際には "github.com/google/uuid" など使ってUUIDを生成してもよい
-	// あるいはユーザーにファイル名を入力させてもよい
-	return fmt.Sprintf("chat_%s.md", nowString())
-}
-
-// nowString は"YYYYMMDD_HHMMSS" 形式の文字列を返す
-func nowString() string {
-	// 現在時刻を"YYYYMMDD_HHMMSS" 形式の文字列に変換
-	return time.Now().Format("20060102_150405")
-}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// writeMarkdown は、役割(role)とメッセージ(message)を Markdown 形式でファイルに追記する関数
+func writeMarkdown(f *os.File, role, message string) {
+	// 役割ごとに、見出しを付ける。
+	// Userは「## User」、Assistantは「## Assistant」
+	f.WriteString(fmt.Sprintf("## %s\n\n%s\n\n", role, message))
+}
+
+// writeToolCall は、アシスタントが要求したツール呼び出し一式を
+// "## Tool Call" 見出し付きの JSON フェンスコードブロックとして追記する。
+func writeToolCall(f *os.File, calls []provider.ToolCall) {
+	data, _ := json.MarshalIndent(calls, "", "  ")
+	fmt.Fprintf(f, "## Tool Call\n\n```json\n%s\n```\n\n", data)
+}
+
+// toolResultRecord は "## Tool Result" ブロックの JSON 本体。
+type toolResultRecord struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+}
+
+// writeToolResult は、ツール実行結果を "## Tool Result" 見出し付きの
+// JSON フェンスコードブロックとして追記する。
+func writeToolResult(f *os.File, callID, content string) {
+	data, _ := json.MarshalIndent(toolResultRecord{CallID: callID, Content: content}, "", "  ")
+	fmt.Fprintf(f, "## Tool Result\n\n```json\n%s\n```\n\n", data)
+}
+
+// extractFencedJSON は "```json\n...\n```" 形式のコードフェンスから中身の
+// JSON 文字列を取り出す。フェンスが無ければそのまま返す。
+func extractFencedJSON(content string) string {
+	lines := strings.Split(content, "\n")
+	start, end := 0, len(lines)
+	if start < end && strings.HasPrefix(strings.TrimSpace(lines[start]), "```") {
+		start++
+	}
+	if end > start && strings.HasPrefix(strings.TrimSpace(lines[end-1]), "```") {
+		end--
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// -----------------------------
+// Markdownをパースして messages に変換する関数
+// -----------------------------
+//
+// commentPrefix/commentSuffix は persistParam が書き込む
+// "<!-- chatmd: key=value -->" 形式のコメントを認識するためのもの。
+const (
+	commentPrefix = "<!-- chatmd: "
+	commentSuffix = " -->"
+)
+
+// parseChatComment は1行が chatmd の設定コメントかどうかを判定し、
+// そうであれば key と value を返す。
+func parseChatComment(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, commentPrefix) || !strings.HasSuffix(line, commentSuffix) {
+		return "", "", false
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(line, commentPrefix), commentSuffix)
+	key, value, ok = strings.Cut(body, "=")
+	return key, value, ok
+}
+
+// parseChatHistory はファイルを読み込み、メッセージ履歴と
+// 設定コメントから復元した Session を返す。
+func parseChatHistory(filename string) ([]provider.Message, *Session, error) {
+	sess := newSession("", "")
+
+	// ファイルを開く
+	f, err := os.Open(filename)
+	if err != nil {
+		// ファイルが存在しないなど
+		if os.IsNotExist(err) {
+			// 無視して空スライスを返す
+			return nil, sess, nil
+		}
+		return nil, sess, err
+	}
+	defer f.Close()
+
+	var messages []provider.Message
+	var currentRole string
+	var currentLines []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// chatmd の設定コメントは Session に反映し、本文には含めない
+		if key, value, ok := parseChatComment(line); ok {
+			sess.applyParam(key, value)
+			continue
+		}
+
+		// 「## 」で始まる行は"役割"とみなす
+		if strings.HasPrefix(line, "## ") {
+			// もし前の役割とメッセージがたまっていたら messages に追加
+			if currentRole != "" && len(currentLines) > 0 {
+				msg := strings.Join(currentLines, "\n")
+				messages = append(messages, convertToMessage(currentRole, msg))
+			}
+			// 新しい役割をセット
+			currentRole = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			// メッセージバッファを初期化
+			currentLines = []string{}
+		} else {
+			// 役割以外の行はメッセージの本文としてためる
+			// （空行でも改行扱いでつなげたいならここで処理）
+			currentLines = append(currentLines, line)
+		}
+	}
+	// ループ終了後に残っていたら最後に追加
+	if currentRole != "" && len(currentLines) > 0 {
+		msg := strings.Join(currentLines, "\n")
+		messages = append(messages, convertToMessage(currentRole, msg))
+	}
+
+	// scanner のエラーチェック
+	if err := scanner.Err(); err != nil {
+		return messages, sess, err
+	}
+
+	// system コメントが記録されていれば、本文中に "## System" が
+	// なくても先頭にシステムメッセージを復元する
+	if sess.System != "" {
+		messages = applySystemMessage(messages, sess.System)
+	}
+
+	return messages, sess, nil
+}
+
+// 役割文字列を provider.Message に変換する小関数。
+// User の本文は ![alt](path) 形式の画像参照を再検出し、対応する
+// Images を持つマルチパートメッセージとして復元する。
+// "Tool Call" / "Tool Result" は writeToolCall / writeToolResult が
+// 書き込んだ JSON フェンスコードブロックを読み戻し、エージェントモードの
+// 再開時に呼び出しグラフ全体を復元できるようにする。
+func convertToMessage(role, content string) provider.Message {
+	switch role {
+	case "Assistant":
+		return provider.Message{Role: "assistant", Content: content}
+	case "System":
+		return provider.Message{Role: "system", Content: content}
+	case "Tool Call":
+		var calls []provider.ToolCall
+		if err := json.Unmarshal([]byte(extractFencedJSON(content)), &calls); err != nil {
+			return provider.Message{Role: "assistant"}
+		}
+		return provider.Message{Role: "assistant", ToolCalls: calls}
+	case "Tool Result":
+		var rec toolResultRecord
+		if err := json.Unmarshal([]byte(extractFencedJSON(content)), &rec); err != nil {
+			return provider.Message{Role: "tool"}
+		}
+		return provider.Message{Role: "tool", Content: rec.Content, ToolCallID: rec.CallID}
+	default:
+		// "User" 以外の未知のロールも User 扱いにする
+		return userMessageWithImages(content)
+	}
+}
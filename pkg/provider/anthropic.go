@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	anthropicAPIBase    = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion = "2023-06-01"
+	anthropicDefaultMax = int64(1024)
+)
+
+// anthropicContentBlock is one block of an Anthropic Messages API
+// message: either {"type":"text","text":"..."} or an image block.
+type anthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// ToAnthropic converts m into the Anthropic Messages API wire format.
+// System messages are not included here -- Anthropic takes the system
+// prompt via a separate top-level "system" field (see Params.System).
+func (m Message) ToAnthropic() anthropicMessage {
+	blocks := []anthropicContentBlock{{Type: "text", Text: m.Content}}
+	for _, img := range m.Images {
+		if strings.HasPrefix(img, "data:") {
+			mime, data, _ := strings.Cut(strings.TrimPrefix(img, "data:"), ";base64,")
+			blocks = append(blocks, anthropicContentBlock{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "base64", MediaType: mime, Data: data},
+			})
+		} else {
+			blocks = append(blocks, anthropicContentBlock{
+				Type:   "image",
+				Source: &anthropicImageSource{Type: "url", URL: img},
+			})
+		}
+	}
+	return anthropicMessage{Role: m.Role, Content: blocks}
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int64              `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []anthropicContentBlock `json:"content"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// AnthropicProvider talks to the Anthropic Messages API directly over
+// HTTP; there is no official Go SDK dependency in this module.
+type AnthropicProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func NewAnthropicProvider(apiKey string) *AnthropicProvider {
+	return &AnthropicProvider{apiKey: apiKey, client: http.DefaultClient}
+}
+
+func (p *AnthropicProvider) Name() string { return "anthropic" }
+
+func (p *AnthropicProvider) buildRequest(messages []Message, params Params, stream bool) anthropicRequest {
+	req := anthropicRequest{
+		Model:       params.Model,
+		System:      params.System,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		MaxTokens:   anthropicDefaultMax,
+		Stream:      stream,
+	}
+	if params.MaxTokens != nil {
+		req.MaxTokens = *params.MaxTokens
+	}
+	for _, m := range messages {
+		if m.Role == "system" {
+			if req.System == "" {
+				req.System = m.Content
+			}
+			continue
+		}
+		req.Messages = append(req.Messages, m.ToAnthropic())
+	}
+	return req
+}
+
+func (p *AnthropicProvider) newHTTPRequest(ctx context.Context, body anthropicRequest) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicAPIBase, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	return httpReq, nil
+}
+
+func (p *AnthropicProvider) Complete(ctx context.Context, messages []Message, params Params) (Message, error) {
+	httpReq, err := p.newHTTPRequest(ctx, p.buildRequest(messages, params, false))
+	if err != nil {
+		return Message{}, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Message{}, err
+	}
+	defer resp.Body.Close()
+
+	var parsed anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Message{}, err
+	}
+	if parsed.Error != nil {
+		return Message{}, fmt.Errorf("anthropic: %s", parsed.Error.Message)
+	}
+
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return Message{Role: "assistant", Content: text.String()}, nil
+}
+
+func (p *AnthropicProvider) Stream(ctx context.Context, messages []Message, params Params) (Stream, error) {
+	httpReq, err := p.newHTTPRequest(ctx, p.buildRequest(messages, params, true))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, anthropicErrorFromBody(resp)
+	}
+	return &anthropicStream{scanner: newSSEScanner(resp.Body)}, nil
+}
+
+// anthropicErrorFromBody reads an error response body (a plain JSON
+// object, not an SSE stream) and turns it into an error, so a non-200
+// status surfaces instead of being silently treated as an empty stream
+// by sseScanner.
+func anthropicErrorFromBody(resp *http.Response) error {
+	var parsed anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil || parsed.Error == nil {
+		return fmt.Errorf("anthropic: unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("anthropic: %s", parsed.Error.Message)
+}
+
+type anthropicStreamDelta struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStream adapts Anthropic's SSE event stream (message_start,
+// content_block_delta, message_stop, ...) to provider.Stream.
+type anthropicStream struct {
+	scanner *sseScanner
+	current string
+	err     error
+}
+
+func (s *anthropicStream) Next() bool {
+	for {
+		evt, ok := s.scanner.Next()
+		if !ok {
+			return false
+		}
+		if evt.Type != "content_block_delta" && evt.Type != "error" {
+			continue
+		}
+		var delta anthropicStreamDelta
+		if err := json.Unmarshal(evt.Data, &delta); err != nil {
+			s.err = err
+			return false
+		}
+		if delta.Error != nil {
+			s.err = fmt.Errorf("anthropic: %s", delta.Error.Message)
+			return false
+		}
+		if delta.Delta.Text == "" {
+			continue
+		}
+		s.current = delta.Delta.Text
+		return true
+	}
+}
+
+func (s *anthropicStream) Current() string { return s.current }
+func (s *anthropicStream) Err() error      { return s.err }
+func (s *anthropicStream) Close() error    { return s.scanner.Close() }
+
+var _ io.Closer = (*anthropicStream)(nil)
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the shape of ~/.chatmd/config.yaml.
+//
+//	default_provider: openai
+//	providers:
+//	  openai:
+//	    api_key: sk-...
+//	    model: gpt-4o
+//	  anthropic:
+//	    api_key: sk-ant-...
+//	    model: claude-3-5-sonnet-20241022
+//	  ollama:
+//	    base_url: http://localhost:11434
+//	    model: llama3
+//	agents:
+//	  coder:
+//	    system: You are a coding assistant for this repository.
+//	    tools: [read_file, write_file, modify_file, list_dir, run_shell]
+type Config struct {
+	DefaultProvider string                    `yaml:"default_provider"`
+	Providers       map[string]ProviderConfig `yaml:"providers"`
+	Agents          map[string]AgentConfig    `yaml:"agents"`
+}
+
+// ProviderConfig holds the per-provider settings needed to construct a
+// ChatProvider: credentials, an optional base URL override (Ollama),
+// and the default model to use for that provider.
+type ProviderConfig struct {
+	APIKey  string `yaml:"api_key"`
+	BaseURL string `yaml:"base_url"`
+	Model   string `yaml:"model"`
+}
+
+// AgentConfig describes a named agent selectable via --agent: a system
+// prompt and the subset of built-in tool names it is allowed to call.
+type AgentConfig struct {
+	System string   `yaml:"system"`
+	Tools  []string `yaml:"tools"`
+}
+
+// ConfigPath returns the default location of chatmd's config file,
+// ~/.chatmd/config.yaml.
+func ConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".chatmd", "config.yaml"), nil
+}
+
+// LoadConfig reads and parses the config file at path. A missing file
+// is not an error; it simply yields a zero-value Config so chatmd can
+// fall back to environment variables and built-in defaults.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// New constructs the ChatProvider named by name (falling back to
+// cfg.DefaultProvider, then "openai") using the matching entry in
+// cfg.Providers.
+func New(name string, cfg Config) (ChatProvider, error) {
+	if name == "" {
+		name = cfg.DefaultProvider
+	}
+	if name == "" {
+		name = "openai"
+	}
+	pc := cfg.Providers[name]
+
+	switch name {
+	case "openai":
+		return NewOpenAIProvider(pc.APIKey), nil
+	case "anthropic":
+		return NewAnthropicProvider(pc.APIKey), nil
+	case "google":
+		return NewGoogleProvider(pc.APIKey), nil
+	case "ollama":
+		return NewOllamaProvider(pc.BaseURL), nil
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+}
+
+// DefaultModel returns the model configured for name, if any.
+func DefaultModel(name string, cfg Config) string {
+	return cfg.Providers[name].Model
+}
@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const googleAPIBase = "https://generativelanguage.googleapis.com/v1beta/models"
+
+// googlePart is one part of a Gemini content turn: a text part, an
+// inline (base64) image part, or a file reference.
+type googlePart struct {
+	Text       string          `json:"text,omitempty"`
+	InlineData *googleInline   `json:"inlineData,omitempty"`
+	FileData   *googleFileData `json:"fileData,omitempty"`
+}
+
+type googleInline struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type googleFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type googleContent struct {
+	Role  string       `json:"role"`
+	Parts []googlePart `json:"parts"`
+}
+
+// ToGemini converts m into a Gemini "Content" turn. Gemini uses "model"
+// rather than "assistant" for the assistant role.
+func (m Message) ToGemini() googleContent {
+	role := m.Role
+	if role == "assistant" {
+		role = "model"
+	}
+	parts := []googlePart{{Text: m.Content}}
+	for _, img := range m.Images {
+		if strings.HasPrefix(img, "data:") {
+			mime, data, _ := strings.Cut(strings.TrimPrefix(img, "data:"), ";base64,")
+			parts = append(parts, googlePart{InlineData: &googleInline{MimeType: mime, Data: data}})
+		} else {
+			// Gemini's generateContent only resolves fileUri values that
+			// come from its own Files API; an arbitrary web URL is passed
+			// through best-effort and may be rejected by the API.
+			parts = append(parts, googlePart{FileData: &googleFileData{FileURI: img}})
+		}
+	}
+	return googleContent{Role: role, Parts: parts}
+}
+
+type googleGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens *int64   `json:"maxOutputTokens,omitempty"`
+}
+
+type googleRequest struct {
+	Contents          []googleContent         `json:"contents"`
+	SystemInstruction *googleContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *googleGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type googleResponse struct {
+	Candidates []struct {
+		Content googleContent `json:"content"`
+	} `json:"candidates"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GoogleProvider talks to the Gemini API directly over HTTP.
+type GoogleProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func NewGoogleProvider(apiKey string) *GoogleProvider {
+	return &GoogleProvider{apiKey: apiKey, client: http.DefaultClient}
+}
+
+func (p *GoogleProvider) Name() string { return "google" }
+
+func (p *GoogleProvider) buildRequest(messages []Message, params Params) googleRequest {
+	req := googleRequest{}
+	system := params.System
+	for _, m := range messages {
+		if m.Role == "system" {
+			if system == "" {
+				system = m.Content
+			}
+			continue
+		}
+		req.Contents = append(req.Contents, m.ToGemini())
+	}
+	if system != "" {
+		req.SystemInstruction = &googleContent{Parts: []googlePart{{Text: system}}}
+	}
+	if params.Temperature != nil || params.TopP != nil || params.MaxTokens != nil {
+		req.GenerationConfig = &googleGenerationConfig{
+			Temperature:     params.Temperature,
+			TopP:            params.TopP,
+			MaxOutputTokens: params.MaxTokens,
+		}
+	}
+	return req
+}
+
+func (p *GoogleProvider) endpoint(model, method string) string {
+	return fmt.Sprintf("%s/%s:%s?key=%s", googleAPIBase, model, method, p.apiKey)
+}
+
+func (p *GoogleProvider) Complete(ctx context.Context, messages []Message, params Params) (Message, error) {
+	data, err := json.Marshal(p.buildRequest(messages, params))
+	if err != nil {
+		return Message{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint(params.Model, "generateContent"), bytes.NewReader(data))
+	if err != nil {
+		return Message{}, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Message{}, err
+	}
+	defer resp.Body.Close()
+
+	var parsed googleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Message{}, err
+	}
+	if parsed.Error != nil {
+		return Message{}, fmt.Errorf("google: %s", parsed.Error.Message)
+	}
+	if len(parsed.Candidates) == 0 {
+		return Message{Role: "assistant"}, nil
+	}
+
+	var text strings.Builder
+	for _, part := range parsed.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return Message{Role: "assistant", Content: text.String()}, nil
+}
+
+func (p *GoogleProvider) Stream(ctx context.Context, messages []Message, params Params) (Stream, error) {
+	data, err := json.Marshal(p.buildRequest(messages, params))
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint(params.Model, "streamGenerateContent")+"&alt=sse", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, googleErrorFromBody(resp)
+	}
+	return &googleStream{scanner: newSSEScanner(resp.Body)}, nil
+}
+
+// googleErrorFromBody reads an error response body (a plain JSON object,
+// not an SSE stream) and turns it into an error, so a non-200 status
+// from streamGenerateContent surfaces instead of being silently treated
+// as an empty stream by sseScanner.
+func googleErrorFromBody(resp *http.Response) error {
+	var parsed googleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil || parsed.Error == nil {
+		return fmt.Errorf("google: unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("google: %s", parsed.Error.Message)
+}
+
+// googleStream adapts Gemini's "alt=sse" streaming response to
+// provider.Stream.
+type googleStream struct {
+	scanner *sseScanner
+	current string
+	err     error
+}
+
+func (s *googleStream) Next() bool {
+	for {
+		evt, ok := s.scanner.Next()
+		if !ok {
+			return false
+		}
+		var parsed googleResponse
+		if err := json.Unmarshal(evt.Data, &parsed); err != nil {
+			s.err = err
+			return false
+		}
+		if parsed.Error != nil {
+			s.err = fmt.Errorf("google: %s", parsed.Error.Message)
+			return false
+		}
+		if len(parsed.Candidates) == 0 {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range parsed.Candidates[0].Content.Parts {
+			text.WriteString(part.Text)
+		}
+		if text.Len() == 0 {
+			continue
+		}
+		s.current = text.String()
+		return true
+	}
+}
+
+func (s *googleStream) Current() string { return s.current }
+func (s *googleStream) Err() error      { return s.err }
+func (s *googleStream) Close() error    { return s.scanner.Close() }
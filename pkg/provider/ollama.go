@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ollamaDefaultBaseURL = "http://localhost:11434"
+
+// ollamaMessage is Ollama's /api/chat message format. Images are raw
+// base64 strings with no "data:" prefix and no mime type.
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// ToOllama converts m into Ollama's /api/chat message format.
+func (m Message) ToOllama() ollamaMessage {
+	msg := ollamaMessage{Role: m.Role, Content: m.Content}
+	for _, img := range m.Images {
+		if strings.HasPrefix(img, "data:") {
+			_, data, _ := strings.Cut(img, ";base64,")
+			msg.Images = append(msg.Images, data)
+		}
+		// Ollama's chat API does not accept remote image URLs, only
+		// inline base64, so non-data-URL references are skipped.
+	}
+	return msg
+}
+
+type ollamaOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	NumPredict  *int64   `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+	Done    bool          `json:"done"`
+	Error   string        `json:"error"`
+}
+
+// OllamaProvider talks to a local Ollama server's /api/chat endpoint.
+type OllamaProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOllamaProvider(baseURL string) *OllamaProvider {
+	if baseURL == "" {
+		baseURL = ollamaDefaultBaseURL
+	}
+	return &OllamaProvider{baseURL: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+func (p *OllamaProvider) Name() string { return "ollama" }
+
+func (p *OllamaProvider) buildRequest(messages []Message, params Params, stream bool) ollamaRequest {
+	req := ollamaRequest{Model: params.Model, Stream: stream}
+	system := params.System
+	for _, m := range messages {
+		if m.Role == "system" {
+			if system == "" {
+				system = m.Content
+			}
+			continue
+		}
+		req.Messages = append(req.Messages, m.ToOllama())
+	}
+	if system != "" {
+		req.Messages = append([]ollamaMessage{{Role: "system", Content: system}}, req.Messages...)
+	}
+	if params.Temperature != nil || params.TopP != nil || params.MaxTokens != nil {
+		req.Options = &ollamaOptions{Temperature: params.Temperature, TopP: params.TopP, NumPredict: params.MaxTokens}
+	}
+	return req
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, messages []Message, params Params) (Message, error) {
+	data, err := json.Marshal(p.buildRequest(messages, params, false))
+	if err != nil {
+		return Message{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return Message{}, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Message{}, err
+	}
+	defer resp.Body.Close()
+
+	var parsed ollamaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Message{}, err
+	}
+	if parsed.Error != "" {
+		return Message{}, fmt.Errorf("ollama: %s", parsed.Error)
+	}
+	return Message{Role: "assistant", Content: parsed.Message.Content}, nil
+}
+
+func (p *OllamaProvider) Stream(ctx context.Context, messages []Message, params Params) (Stream, error) {
+	data, err := json.Marshal(p.buildRequest(messages, params, true))
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("content-type", "application/json")
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	return &ollamaStream{rc: resp.Body, scn: bufio.NewScanner(resp.Body)}, nil
+}
+
+// ollamaStream adapts Ollama's newline-delimited JSON stream to
+// provider.Stream.
+type ollamaStream struct {
+	rc      interface{ Close() error }
+	scn     *bufio.Scanner
+	current string
+	err     error
+}
+
+func (s *ollamaStream) Next() bool {
+	for s.scn.Scan() {
+		line := s.scn.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var parsed ollamaResponse
+		if err := json.Unmarshal(line, &parsed); err != nil {
+			s.err = err
+			return false
+		}
+		if parsed.Error != "" {
+			s.err = fmt.Errorf("ollama: %s", parsed.Error)
+			return false
+		}
+		if parsed.Message.Content == "" {
+			continue
+		}
+		s.current = parsed.Message.Content
+		return true
+	}
+	return false
+}
+
+func (s *ollamaStream) Current() string { return s.current }
+func (s *ollamaStream) Err() error      { return s.err }
+func (s *ollamaStream) Close() error    { return s.rc.Close() }
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
+)
+
+// ToOpenAI converts m into the message type the openai-go SDK expects.
+func (m Message) ToOpenAI() openai.ChatCompletionMessageParamUnion {
+	switch m.Role {
+	case "system":
+		return openai.SystemMessage(m.Content)
+	case "tool":
+		return openai.ToolMessage(m.ToolCallID, m.Content)
+	case "assistant":
+		if len(m.ToolCalls) > 0 {
+			calls := make([]openai.ChatCompletionMessageToolCallParam, 0, len(m.ToolCalls))
+			for _, tc := range m.ToolCalls {
+				calls = append(calls, openai.ChatCompletionMessageToolCallParam{
+					ID:   openai.F(tc.ID),
+					Type: openai.F(openai.ChatCompletionMessageToolCallTypeFunction),
+					Function: openai.F(openai.ChatCompletionMessageToolCallFunctionParam{
+						Name:      openai.F(tc.Name),
+						Arguments: openai.F(tc.Arguments),
+					}),
+				})
+			}
+			return openai.ChatCompletionAssistantMessageParam{
+				Role:      openai.F(openai.ChatCompletionAssistantMessageParamRoleAssistant),
+				ToolCalls: openai.F(calls),
+			}
+		}
+		return openai.AssistantMessage(m.Content)
+	default:
+		if len(m.Images) == 0 {
+			return openai.UserMessage(m.Content)
+		}
+		parts := []openai.ChatCompletionContentPartUnionParam{openai.TextPart(m.Content)}
+		for _, img := range m.Images {
+			parts = append(parts, openai.ImagePart(img))
+		}
+		return openai.UserMessageParts(parts...)
+	}
+}
+
+// OpenAIProvider implements ChatProvider on top of the official
+// openai-go client.
+type OpenAIProvider struct {
+	client *openai.Client
+}
+
+// NewOpenAIProvider constructs a provider that talks to the OpenAI API.
+// If apiKey is empty, the client falls back to the OPENAI_API_KEY
+// environment variable, matching openai.NewClient's own default.
+func NewOpenAIProvider(apiKey string) *OpenAIProvider {
+	opts := []option.RequestOption{}
+	if apiKey != "" {
+		opts = append(opts, option.WithAPIKey(apiKey))
+	}
+	return &OpenAIProvider{client: openai.NewClient(opts...)}
+}
+
+func (p *OpenAIProvider) Name() string { return "openai" }
+
+func (p *OpenAIProvider) newParams(messages []Message, params Params) openai.ChatCompletionNewParams {
+	payload := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, m := range messages {
+		payload = append(payload, m.ToOpenAI())
+	}
+	req := openai.ChatCompletionNewParams{
+		Messages: openai.F(payload),
+		Model:    openai.F(openai.ChatModel(params.Model)),
+	}
+	if params.Temperature != nil {
+		req.Temperature = openai.F(*params.Temperature)
+	}
+	if params.TopP != nil {
+		req.TopP = openai.F(*params.TopP)
+	}
+	if params.MaxTokens != nil {
+		req.MaxTokens = openai.F(*params.MaxTokens)
+	}
+	return req
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, messages []Message, params Params) (Message, error) {
+	completion, err := p.client.Chat.Completions.New(ctx, p.newParams(messages, params))
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Role: "assistant", Content: completion.Choices[0].Message.Content}, nil
+}
+
+// CompleteWithTools implements ToolCaller by passing tools as
+// openai.ChatCompletionToolParam entries alongside the usual request.
+func (p *OpenAIProvider) CompleteWithTools(ctx context.Context, messages []Message, params Params, tools []ToolSpec) (ToolResult, error) {
+	req := p.newParams(messages, params)
+	if len(tools) > 0 {
+		toolParams := make([]openai.ChatCompletionToolParam, 0, len(tools))
+		for _, t := range tools {
+			toolParams = append(toolParams, openai.ChatCompletionToolParam{
+				Type: openai.F(openai.ChatCompletionToolTypeFunction),
+				Function: openai.F(shared.FunctionDefinitionParam{
+					Name:        openai.F(t.Name),
+					Description: openai.F(t.Description),
+					Parameters:  openai.F(shared.FunctionParameters(t.Parameters)),
+				}),
+			})
+		}
+		req.Tools = openai.F(toolParams)
+	}
+
+	completion, err := p.client.Chat.Completions.New(ctx, req)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	choice := completion.Choices[0].Message
+	if len(choice.ToolCalls) > 0 {
+		calls := make([]ToolCall, 0, len(choice.ToolCalls))
+		for _, tc := range choice.ToolCalls {
+			calls = append(calls, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+		}
+		return ToolResult{ToolCalls: calls}, nil
+	}
+	return ToolResult{Message: Message{Role: "assistant", Content: choice.Content}}, nil
+}
+
+func (p *OpenAIProvider) Stream(ctx context.Context, messages []Message, params Params) (Stream, error) {
+	stream := p.client.Chat.Completions.NewStreaming(ctx, p.newParams(messages, params))
+	return &openAIStream{stream: stream}, nil
+}
+
+// openAIStream adapts openai-go's ssestream.Stream to provider.Stream.
+type openAIStream struct {
+	stream interface {
+		Next() bool
+		Current() openai.ChatCompletionChunk
+		Err() error
+		Close() error
+	}
+	current string
+}
+
+func (s *openAIStream) Next() bool {
+	for s.stream.Next() {
+		chunk := s.stream.Current()
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		s.current = delta
+		return true
+	}
+	return false
+}
+
+func (s *openAIStream) Current() string { return s.current }
+func (s *openAIStream) Err() error      { return s.stream.Err() }
+func (s *openAIStream) Close() error    { return s.stream.Close() }
@@ -0,0 +1,102 @@
+// Package provider defines a backend-agnostic interface for chat
+// completion APIs (OpenAI, Anthropic, Google Gemini, Ollama) so that
+// chatmd can switch between them without the rest of the program caring
+// which one is in use.
+package provider
+
+import "context"
+
+// Message is chatmd's internal, provider-agnostic representation of a
+// single chat turn. It is the type that flows through the REPL loop and
+// the Markdown persistence layer; each provider adapts it to its own
+// wire format (see ToOpenAI in openai.go, ToAnthropic in anthropic.go,
+// etc.).
+type Message struct {
+	// Role is one of "system", "user", "assistant", or "tool".
+	Role string
+	// Content is the plain-text body of the message. For a "tool" role
+	// message this is the tool's result.
+	Content string
+	// Images holds any image references attached to a user turn, as
+	// either a "data:<mime>;base64,<...>" URL or a remote http(s) URL.
+	Images []string
+	// ToolCalls holds the tool calls requested by the model on an
+	// "assistant" message; empty for a plain text reply.
+	ToolCalls []ToolCall
+	// ToolCallID pairs a "tool" role message with the ToolCall.ID it is
+	// the result of.
+	ToolCallID string
+}
+
+// ToolSpec describes a callable tool in provider-agnostic form: a name,
+// a description for the model, and a JSON Schema for its arguments.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall is one function call the model asked to make.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string // raw JSON, as generated by the model
+}
+
+// ToolResult is what CompleteWithTools returns: either a final text
+// reply (ToolCalls empty) or a batch of tool calls to execute before
+// continuing the conversation.
+type ToolResult struct {
+	Message   Message
+	ToolCalls []ToolCall
+}
+
+// Params holds the generation parameters that are common across
+// providers. A nil pointer means "use the provider's default".
+type Params struct {
+	Model       string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   *int64
+	// System is the system prompt. Providers that accept a dedicated
+	// system field (e.g. Anthropic) use this instead of a "system" role
+	// message in Messages.
+	System string
+}
+
+// Stream is an iterator over the incremental pieces of an assistant
+// reply, modelled after openai-go's ssestream.Stream so callers can use
+// the same Next/Current/Err/Close loop regardless of provider.
+type Stream interface {
+	// Next advances to the next chunk, returning false when the stream
+	// is exhausted or an error occurred (see Err).
+	Next() bool
+	// Current returns the text delta for the chunk most recently
+	// advanced to by Next.
+	Current() string
+	// Err returns the first error encountered, if any.
+	Err() error
+	// Close releases the underlying connection.
+	Close() error
+}
+
+// ChatProvider is implemented by every supported backend.
+type ChatProvider interface {
+	// Name identifies the provider, e.g. "openai", "anthropic".
+	Name() string
+	// Complete requests a full assistant reply in one shot.
+	Complete(ctx context.Context, messages []Message, params Params) (Message, error)
+	// Stream requests an assistant reply and returns it incrementally.
+	Stream(ctx context.Context, messages []Message, params Params) (Stream, error)
+}
+
+// ToolCaller is implemented by providers that support function/tool
+// calling (currently only OpenAI). Callers should type-assert a
+// ChatProvider to ToolCaller before entering agent mode.
+type ToolCaller interface {
+	// CompleteWithTools requests a reply with tools available to the
+	// model. If the model wants to call one or more tools, the returned
+	// ToolResult.ToolCalls is non-empty and Message is the zero value;
+	// otherwise Message holds the final plain-text reply.
+	CompleteWithTools(ctx context.Context, messages []Message, params Params, tools []ToolSpec) (ToolResult, error)
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildRequest は role=="system" のメッセージをシステムプロンプト用の
+// フィールドへ寄せ、通常のメッセージ列には含めないことを各プロバイダで
+// 確認する。params.System とメッセージ中の system ロールが両方ある
+// 場合は params.System を優先し、二重送信にならないことも併せて見る。
+
+func TestAnthropicBuildRequestDedupesSystemMessage(t *testing.T) {
+	p := &AnthropicProvider{}
+	messages := []Message{
+		{Role: "system", Content: "from message"},
+		{Role: "user", Content: "hi"},
+	}
+	req := p.buildRequest(messages, Params{System: "from params"}, false)
+	if req.System != "from params" {
+		t.Fatalf("got System %q, want params.System to win", req.System)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Role != "user" {
+		t.Fatalf("system-role message must be filtered out of Messages, got %+v", req.Messages)
+	}
+}
+
+func TestAnthropicBuildRequestFallsBackToMessageSystem(t *testing.T) {
+	p := &AnthropicProvider{}
+	messages := []Message{{Role: "system", Content: "from message"}}
+	req := p.buildRequest(messages, Params{}, false)
+	if req.System != "from message" {
+		t.Fatalf("got System %q, want %q", req.System, "from message")
+	}
+}
+
+func TestGoogleBuildRequestDedupesSystemMessage(t *testing.T) {
+	p := &GoogleProvider{}
+	messages := []Message{
+		{Role: "system", Content: "from message"},
+		{Role: "user", Content: "hi"},
+	}
+	req := p.buildRequest(messages, Params{System: "from params"})
+	if req.SystemInstruction == nil || len(req.SystemInstruction.Parts) != 1 || req.SystemInstruction.Parts[0].Text != "from params" {
+		t.Fatalf("got SystemInstruction %+v, want params.System to win", req.SystemInstruction)
+	}
+	if len(req.Contents) != 1 || req.Contents[0].Role != "user" {
+		t.Fatalf("system-role message must be filtered out of Contents, got %+v", req.Contents)
+	}
+}
+
+func TestOllamaBuildRequestDedupesSystemMessage(t *testing.T) {
+	p := &OllamaProvider{}
+	messages := []Message{
+		{Role: "system", Content: "from message"},
+		{Role: "user", Content: "hi"},
+	}
+	req := p.buildRequest(messages, Params{System: "from params"}, false)
+
+	systemCount := 0
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			systemCount++
+			if m.Content != "from params" {
+				t.Fatalf("got system message content %q, want params.System to win", m.Content)
+			}
+		}
+	}
+	if systemCount != 1 {
+		t.Fatalf("got %d system messages in request, want exactly 1 (no duplicate)", systemCount)
+	}
+	if len(req.Messages) != 2 {
+		t.Fatalf("got %d messages, want 2 (system + user), got %+v", len(req.Messages), req.Messages)
+	}
+}
+
+func TestOllamaBuildRequestNoSystemPrompt(t *testing.T) {
+	p := &OllamaProvider{}
+	messages := []Message{{Role: "user", Content: "hi"}}
+	req := p.buildRequest(messages, Params{}, false)
+	for _, m := range req.Messages {
+		if m.Role == "system" {
+			t.Fatalf("no system prompt was configured, but got a system message: %+v", m)
+		}
+	}
+}
+
+func TestSSEScannerParsesEvents(t *testing.T) {
+	body := "event: message_start\ndata: {\"a\":1}\n\nevent: content_block_delta\ndata: {\"b\":2}\n\n"
+	scn := newSSEScanner(io.NopCloser(strings.NewReader(body)))
+
+	evt, ok := scn.Next()
+	if !ok || evt.Type != "message_start" || string(evt.Data) != `{"a":1}` {
+		t.Fatalf("got %+v, ok=%v", evt, ok)
+	}
+	evt, ok = scn.Next()
+	if !ok || evt.Type != "content_block_delta" || string(evt.Data) != `{"b":2}` {
+		t.Fatalf("got %+v, ok=%v", evt, ok)
+	}
+	if _, ok := scn.Next(); ok {
+		t.Fatal("expected no more events")
+	}
+}
+
+func TestConfigNewUnknownProvider(t *testing.T) {
+	if _, err := New("nonexistent", Config{}); err == nil {
+		t.Fatal("expected an error for an unknown provider name")
+	}
+}
+
+func TestConfigNewDefaultsToOpenAI(t *testing.T) {
+	p, err := New("", Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name() != "openai" {
+		t.Fatalf("got provider %q, want openai", p.Name())
+	}
+}
+
+func TestDefaultModel(t *testing.T) {
+	cfg := Config{Providers: map[string]ProviderConfig{"openai": {Model: "gpt-4o"}}}
+	if got := DefaultModel("openai", cfg); got != "gpt-4o" {
+		t.Fatalf("got %q, want gpt-4o", got)
+	}
+	if got := DefaultModel("anthropic", cfg); got != "" {
+		t.Fatalf("got %q, want empty string for unconfigured provider", got)
+	}
+}
+
+func TestLoadConfigMissingFileIsNotAnError(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "does-not-exist.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DefaultProvider != "" {
+		t.Fatalf("got %+v, want zero-value Config", cfg)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	yamlContent := "default_provider: anthropic\nproviders:\n  anthropic:\n    api_key: sk-ant-test\n    model: claude-3-5-sonnet-20241022\n"
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DefaultProvider != "anthropic" {
+		t.Fatalf("got DefaultProvider %q, want anthropic", cfg.DefaultProvider)
+	}
+	if cfg.Providers["anthropic"].Model != "claude-3-5-sonnet-20241022" {
+		t.Fatalf("got %+v", cfg.Providers["anthropic"])
+	}
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// sseEvent is one "event: .../data: ..." block of a text/event-stream
+// response, as used by the Anthropic and Gemini streaming endpoints.
+type sseEvent struct {
+	Type string
+	Data []byte
+}
+
+// sseScanner is a minimal Server-Sent Events reader, just enough to
+// drive the streaming responses chatmd consumes. It intentionally does
+// not aim to be a general-purpose SSE client.
+type sseScanner struct {
+	rc  io.ReadCloser
+	scn *bufio.Scanner
+}
+
+func newSSEScanner(rc io.ReadCloser) *sseScanner {
+	return &sseScanner{rc: rc, scn: bufio.NewScanner(rc)}
+}
+
+// Next reads the next event, returning false at EOF or on a read error.
+func (s *sseScanner) Next() (sseEvent, bool) {
+	event := ""
+	data := bytes.NewBuffer(nil)
+
+	for s.scn.Scan() {
+		line := s.scn.Bytes()
+		if len(line) == 0 {
+			if data.Len() == 0 && event == "" {
+				continue
+			}
+			return sseEvent{Type: event, Data: data.Bytes()}, true
+		}
+
+		name, value, _ := bytes.Cut(line, []byte(":"))
+		if len(value) > 0 && value[0] == ' ' {
+			value = value[1:]
+		}
+		switch string(name) {
+		case "event":
+			event = string(value)
+		case "data":
+			data.Write(value)
+		}
+	}
+	return sseEvent{}, false
+}
+
+func (s *sseScanner) Close() error { return s.rc.Close() }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// Session は対話全体を通して保持される API パラメータを表す。
+// スラッシュコマンドで変更された値はここに反映され、
+// 以降の provider.Params の組み立てに使われる。
+type Session struct {
+	Provider    string
+	Model       string
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   *int64
+	System      string
+}
+
+// newSession はデフォルト設定の Session を生成する。
+func newSession(providerName, model string) *Session {
+	return &Session{
+		Provider: providerName,
+		Model:    model,
+	}
+}
+
+// params は現在の Session の設定を反映した provider.Params を組み立てる。
+func (s *Session) params() provider.Params {
+	return provider.Params{
+		Model:       s.Model,
+		Temperature: s.Temperature,
+		TopP:        s.TopP,
+		MaxTokens:   s.MaxTokens,
+		System:      s.System,
+	}
+}
+
+// applyParam は "<!-- chatmd: key=value -->" から復元した key/value を
+// Session に適用する。未知の key は無視する。
+func (s *Session) applyParam(key, value string) {
+	switch key {
+	case "provider":
+		s.Provider = value
+	case "model":
+		s.Model = value
+	case "temperature":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			s.Temperature = &v
+		}
+	case "top_p":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			s.TopP = &v
+		}
+	case "max_tokens":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			s.MaxTokens = &v
+		}
+	case "system":
+		s.System = value
+	}
+}
+
+// applySystemMessage は messages の先頭にあるシステムメッセージを text で
+// 置き換える。先頭がシステムメッセージでなければ、先頭に新規挿入する。
+func applySystemMessage(messages []provider.Message, text string) []provider.Message {
+	if len(messages) > 0 && messages[0].Role == "system" {
+		messages[0].Content = text
+		return messages
+	}
+	return append([]provider.Message{{Role: "system", Content: text}}, messages...)
+}
+
+// estimateTokens は簡易的なトークン数の推定を行う。
+// 正確なトークナイザは使わず、英数字がおよそ4文字で1トークンという
+// 経験則に基づく概算値を返す。
+func estimateTokens(s string) int {
+	return (len([]rune(s)) + 3) / 4
+}
+
+// summarizeContext は現在の会話履歴のメッセージ数とロールごとの
+// 概算トークン数を表示する。
+func summarizeContext(messages []provider.Message) {
+	fmt.Println("-----------------------------------")
+	fmt.Printf("会話履歴: %d メッセージ\n", len(messages))
+	total := 0
+	for i, m := range messages {
+		tokens := estimateTokens(m.Content)
+		total += tokens
+		fmt.Printf("  [%d] %-9s 約%dトークン\n", i, m.Role, tokens)
+	}
+	fmt.Printf("合計: 約%dトークン（簡易推定）\n", total)
+	fmt.Println("-----------------------------------")
+}
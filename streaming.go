@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tochiji/chatmd/pkg/provider"
+)
+
+// runCompletion は ChatProvider.Stream を使って応答を取得し、届いた
+// トークンを逐次 stdout に出力しながら蓄積する。生成中に SIGINT
+// (Ctrl-C) を受け取った場合はストリームを中断し、それまでに受信できた
+// 部分的な応答を cancelled=true と共に返す（エラーにはしない）。
+// これにより入力プロンプトへ戻ることができる。
+func runCompletion(ctx context.Context, p provider.ChatProvider, messages []provider.Message, params provider.Params) (content string, cancelled bool, err error) {
+	genCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	// cancelled はシグナル処理用 goroutine と呼び出し元の双方から触れるため、
+	// bare な bool ではなく atomic.Bool で同期する。
+	var cancelledFlag atomic.Bool
+	go func() {
+		select {
+		case <-sigCh:
+			cancelledFlag.Store(true)
+			cancel()
+		case <-genCtx.Done():
+		}
+	}()
+
+	stream, err := p.Stream(genCtx, messages, params)
+	if err != nil {
+		return "", false, err
+	}
+	defer stream.Close()
+
+	var buf strings.Builder
+	for stream.Next() {
+		delta := stream.Current()
+		fmt.Print(delta)
+		buf.WriteString(delta)
+	}
+	fmt.Println()
+
+	cancelled = cancelledFlag.Load()
+
+	if streamErr := stream.Err(); streamErr != nil {
+		if cancelled {
+			// Ctrl-C によるキャンセルなので、エラーとしては扱わず
+			// 受信済みの部分的な応答をそのまま返す。
+			return buf.String(), true, nil
+		}
+		return buf.String(), false, streamErr
+	}
+
+	return buf.String(), cancelled, nil
+}